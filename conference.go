@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// ConferenceMatch is the normalized result of matching an event against a
+// ConferenceProvider.
+type ConferenceMatch struct {
+	Provider  string
+	JoinURL   string
+	MeetingID string
+	Passcode  string
+	DialIn    string
+}
+
+// ConferenceProvider inspects an event's conference-related fields
+// (ConferenceData entry points, Location, Description, HangoutLink) and
+// reports whether it recognizes a meeting link for its service.
+type ConferenceProvider interface {
+	Match(event calendar.Event) (ConferenceMatch, bool)
+}
+
+// conferenceProviders is tried in order; the first match wins.
+var conferenceProviders = []ConferenceProvider{
+	meetProvider{},
+	zoomProvider{},
+	teamsProvider{},
+	webexProvider{},
+}
+
+// matchConference returns the first provider's match for event, if any.
+func matchConference(event calendar.Event) (ConferenceMatch, bool) {
+	for _, p := range conferenceProviders {
+		if m, ok := p.Match(event); ok {
+			return m, true
+		}
+	}
+	return ConferenceMatch{}, false
+}
+
+// confEntryPoints returns event's ConferenceData entry points, or nil.
+func confEntryPoints(event calendar.Event) []*calendar.EntryPoint {
+	if event.ConferenceData == nil {
+		return nil
+	}
+	return event.ConferenceData.EntryPoints
+}
+
+// searchSources returns every free-text field a ConferenceProvider should
+// scan for its service's URL, beyond the structured EntryPoints.
+func searchSources(event calendar.Event) []string {
+	var sources []string
+	if event.HangoutLink != "" {
+		sources = append(sources, event.HangoutLink)
+	}
+	if event.Location != "" {
+		sources = append(sources, event.Location)
+	}
+	if event.Description != "" {
+		sources = append(sources, event.Description)
+	}
+	return sources
+}
+
+func passcodeFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("pwd")
+}
+
+// hasHost reports whether rawURL's host is exactly domain or a subdomain of
+// it, so a lookalike like "evilzoom.us" can't be mistaken for "zoom.us" the
+// way a plain strings.Contains would.
+func hasHost(rawURL, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+type meetProvider struct{}
+
+var meetURLRe = regexp.MustCompile(`https?://meet\.google\.com/\S+`)
+
+func (meetProvider) Match(event calendar.Event) (ConferenceMatch, bool) {
+	if event.HangoutLink != "" {
+		return ConferenceMatch{Provider: "Google Meet", JoinURL: event.HangoutLink}, true
+	}
+	for _, ep := range confEntryPoints(event) {
+		if hasHost(ep.Uri, "meet.google.com") {
+			return ConferenceMatch{Provider: "Google Meet", JoinURL: ep.Uri}, true
+		}
+	}
+	for _, s := range searchSources(event) {
+		if u := meetURLRe.FindString(s); u != "" {
+			return ConferenceMatch{Provider: "Google Meet", JoinURL: u}, true
+		}
+	}
+	return ConferenceMatch{}, false
+}
+
+type zoomProvider struct{}
+
+var (
+	zoomURLRe   = regexp.MustCompile(`https?://(?:[\w-]+\.)?zoom\.us/j/\d+(\?\S*)?`)
+	zoomMtgIDRe = regexp.MustCompile(`zoom\.us/j/(\d+)`)
+)
+
+func (zoomProvider) Match(event calendar.Event) (ConferenceMatch, bool) {
+	for _, ep := range confEntryPoints(event) {
+		if hasHost(ep.Uri, "zoom.us") {
+			m := ConferenceMatch{Provider: "Zoom", JoinURL: ep.Uri, MeetingID: ep.MeetingCode, Passcode: ep.Password}
+			if m.Passcode == "" {
+				m.Passcode = passcodeFromURL(ep.Uri)
+			}
+			return m, true
+		}
+	}
+	for _, s := range searchSources(event) {
+		u := zoomURLRe.FindString(s)
+		if u == "" {
+			continue
+		}
+		m := ConferenceMatch{Provider: "Zoom", JoinURL: u, Passcode: passcodeFromURL(u)}
+		if id := zoomMtgIDRe.FindStringSubmatch(u); id != nil {
+			m.MeetingID = id[1]
+		}
+		return m, true
+	}
+	return ConferenceMatch{}, false
+}
+
+type teamsProvider struct{}
+
+var teamsURLRe = regexp.MustCompile(`https?://teams\.microsoft\.com/l/meetup-join/\S+`)
+
+func (teamsProvider) Match(event calendar.Event) (ConferenceMatch, bool) {
+	for _, ep := range confEntryPoints(event) {
+		if hasHost(ep.Uri, "teams.microsoft.com") && strings.Contains(ep.Uri, "/l/meetup-join") {
+			return ConferenceMatch{Provider: "Microsoft Teams", JoinURL: ep.Uri}, true
+		}
+	}
+	for _, s := range searchSources(event) {
+		if u := teamsURLRe.FindString(s); u != "" {
+			return ConferenceMatch{Provider: "Microsoft Teams", JoinURL: u}, true
+		}
+	}
+	return ConferenceMatch{}, false
+}
+
+type webexProvider struct{}
+
+var webexURLRe = regexp.MustCompile(`https?://(?:[\w-]+\.)?webex\.com/\S+`)
+
+func (webexProvider) Match(event calendar.Event) (ConferenceMatch, bool) {
+	for _, ep := range confEntryPoints(event) {
+		if hasHost(ep.Uri, "webex.com") {
+			return ConferenceMatch{Provider: "Webex", JoinURL: ep.Uri, MeetingID: ep.MeetingCode, Passcode: ep.Password}, true
+		}
+	}
+	for _, s := range searchSources(event) {
+		if u := webexURLRe.FindString(s); u != "" {
+			return ConferenceMatch{Provider: "Webex", JoinURL: u}, true
+		}
+	}
+	return ConferenceMatch{}, false
+}
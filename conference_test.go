@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestZoomURLReBoundary(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare domain", "join at https://zoom.us/j/1234567890 now", "https://zoom.us/j/1234567890"},
+		{"real subdomain", "https://acme.zoom.us/j/1234567890?pwd=abc", "https://acme.zoom.us/j/1234567890?pwd=abc"},
+		{"lookalike domain doesn't match", "https://evilzoom.us/j/1234567890", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zoomURLRe.FindString(tt.in); got != tt.want {
+				t.Errorf("zoomURLRe.FindString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebexURLReBoundary(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare domain", "https://webex.com/meet/abc", "https://webex.com/meet/abc"},
+		{"real subdomain", "https://acme.webex.com/meet/abc", "https://acme.webex.com/meet/abc"},
+		{"lookalike domain doesn't match", "https://notwebex.com/x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webexURLRe.FindString(tt.in); got != tt.want {
+				t.Errorf("webexURLRe.FindString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		domain string
+		want   bool
+	}{
+		{"exact host", "https://zoom.us/j/123", "zoom.us", true},
+		{"real subdomain", "https://acme.zoom.us/j/123", "zoom.us", true},
+		{"lookalike host doesn't match", "https://evilzoom.us/j/123", "zoom.us", false},
+		{"lookalike suffix without dot doesn't match", "https://notwebex.com/x", "webex.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasHost(tt.rawURL, tt.domain); got != tt.want {
+				t.Errorf("hasHost(%q, %q) = %v, want %v", tt.rawURL, tt.domain, got, tt.want)
+			}
+		})
+	}
+}
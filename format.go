@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// ConferenceEntryPoint is the subset of calendar.EntryPoint that's useful to
+// downstream consumers of structured output.
+type ConferenceEntryPoint struct {
+	Type  string `json:"type,omitempty"`
+	URI   string `json:"uri,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+func conferenceEntryPoints(conf *calendar.ConferenceData) []ConferenceEntryPoint {
+	if conf == nil {
+		return nil
+	}
+	points := make([]ConferenceEntryPoint, 0, len(conf.EntryPoints))
+	for _, ep := range conf.EntryPoints {
+		points = append(points, ConferenceEntryPoint{Type: ep.EntryPointType, URI: ep.Uri, Label: ep.Label})
+	}
+	return points
+}
+
+// FormattedEvent is the format-agnostic view of a calendar event that a
+// Formatter renders.
+type FormattedEvent struct {
+	Start, End     time.Time
+	Summary        string
+	Location       string
+	URL            string
+	ResponseStatus string
+	HtmlLink       string
+	Conference     []ConferenceEntryPoint
+	CalendarID     string
+	CalendarName   string
+}
+
+// Formatter renders a list of events for a single -o output mode. multiCal
+// indicates whether more than one calendar was queried, so implementations
+// that only tag events when needed (text) can decide whether to.
+type Formatter interface {
+	Format(w io.Writer, events []FormattedEvent, multiCal bool) error
+}
+
+func formatterFor(name string) (Formatter, error) {
+	switch name {
+	case "", "text":
+		return textFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "ics":
+		return icsFormatter{}, nil
+	case "tsv":
+		return tsvFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -o format %q, want text, json, ics, or tsv", name)
+	}
+}
+
+// textFormatter reproduces the original human-readable listing, grouped by
+// day with a separator line between days.
+type textFormatter struct{}
+
+func (textFormatter) Format(w io.Writer, events []FormattedEvent, multiCal bool) error {
+	if len(events) == 0 {
+		fmt.Fprintln(w, "No upcoming events found.")
+		return nil
+	}
+	prevDay := truncDay(time.Now())
+	for _, e := range events {
+		day := truncDay(e.Start)
+		if day != prevDay {
+			fmt.Fprintln(w, "----------------------")
+			prevDay = day
+		}
+		fmt.Fprintf(w, "%s-%s %-40s %s", e.Start.Format("2006-01-02 15:04"), e.End.Format("15:04"), e.Summary, e.URL)
+		if multiCal {
+			fmt.Fprintf(w, " (%s)", e.CalendarName)
+		}
+		if e.ResponseStatus != "" && e.ResponseStatus != "accepted" {
+			fmt.Fprintf(w, " [%s: %s]", e.ResponseStatus, e.HtmlLink)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// jsonFormatter emits one JSON array of events.
+type jsonFormatter struct{}
+
+type jsonEvent struct {
+	Start          string                 `json:"start"`
+	End            string                 `json:"end"`
+	Summary        string                 `json:"summary"`
+	Location       string                 `json:"location,omitempty"`
+	URL            string                 `json:"url,omitempty"`
+	ResponseStatus string                 `json:"responseStatus,omitempty"`
+	HtmlLink       string                 `json:"htmlLink,omitempty"`
+	Conference     []ConferenceEntryPoint `json:"conferenceEntryPoints,omitempty"`
+	CalendarID     string                 `json:"calendarId"`
+}
+
+func (jsonFormatter) Format(w io.Writer, events []FormattedEvent, _ bool) error {
+	out := make([]jsonEvent, len(events))
+	for i, e := range events {
+		out[i] = jsonEvent{
+			Start:          e.Start.Format(time.RFC3339),
+			End:            e.End.Format(time.RFC3339),
+			Summary:        e.Summary,
+			Location:       e.Location,
+			URL:            e.URL,
+			ResponseStatus: e.ResponseStatus,
+			HtmlLink:       e.HtmlLink,
+			Conference:     e.Conference,
+			CalendarID:     e.CalendarID,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// tsvFormatter mirrors the text formatter's columns, one event per line.
+type tsvFormatter struct{}
+
+func (tsvFormatter) Format(w io.Writer, events []FormattedEvent, _ bool) error {
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Start.Format("2006-01-02 15:04"), e.End.Format("15:04"),
+			e.Summary, e.URL, e.ResponseStatus, e.HtmlLink, e.CalendarName)
+	}
+	return nil
+}
+
+// icsFormatter emits a VCALENDAR/VEVENT stream suitable for re-import.
+type icsFormatter struct{}
+
+var icsEscaper = strings.NewReplacer("\\", "\\\\", ",", "\\,", ";", "\\;", "\n", "\\n")
+
+func (icsFormatter) Format(w io.Writer, events []FormattedEvent, _ bool) error {
+	dtstamp := time.Now().UTC().Format("20060102T150405Z")
+	fmt.Fprintln(w, "BEGIN:VCALENDAR")
+	fmt.Fprintln(w, "VERSION:2.0")
+	fmt.Fprintln(w, "PRODID:-//next-meeting//EN")
+	for _, e := range events {
+		fmt.Fprintln(w, "BEGIN:VEVENT")
+		fmt.Fprintf(w, "UID:%d-%s@next-meeting\n", e.Start.Unix(), e.CalendarID)
+		fmt.Fprintf(w, "DTSTAMP:%s\n", dtstamp)
+		fmt.Fprintf(w, "DTSTART:%s\n", e.Start.UTC().Format("20060102T150405Z"))
+		fmt.Fprintf(w, "DTEND:%s\n", e.End.UTC().Format("20060102T150405Z"))
+		fmt.Fprintf(w, "SUMMARY:%s\n", icsEscaper.Replace(e.Summary))
+		if e.Location != "" {
+			fmt.Fprintf(w, "LOCATION:%s\n", icsEscaper.Replace(e.Location))
+		}
+		if e.URL != "" {
+			fmt.Fprintf(w, "URL:%s\n", e.URL)
+		}
+		fmt.Fprintln(w, "END:VEVENT")
+	}
+	fmt.Fprintln(w, "END:VCALENDAR")
+	return nil
+}
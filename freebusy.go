@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// workHours is a daily [start, end) window expressed as minutes since midnight.
+type workHours struct {
+	startMin, endMin int
+}
+
+func parseWorkHours(s string) (workHours, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return workHours{}, fmt.Errorf("invalid -hours %q, want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return workHours{}, fmt.Errorf("invalid -hours %q: %w", s, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return workHours{}, fmt.Errorf("invalid -hours %q: %w", s, err)
+	}
+	if end <= start {
+		return workHours{}, fmt.Errorf("invalid -hours %q: end must be after start", s)
+	}
+	return workHours{startMin: start, endMin: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var weekdayOrder = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+var weekdayByName = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWorkdays parses a day set like "mon-fri" or "mon,wed,fri".
+func parseWorkdays(s string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if i := strings.Index(part, "-"); i >= 0 {
+			from, to := dayIndex(part[:i]), dayIndex(part[i+1:])
+			if from < 0 || to < 0 {
+				return nil, fmt.Errorf("invalid -workdays %q", s)
+			}
+			for d := from; ; d = (d + 1) % 7 {
+				days[weekdayByName[weekdayOrder[d]]] = true
+				if d == to {
+					break
+				}
+			}
+			continue
+		}
+		d, ok := weekdayByName[part]
+		if !ok {
+			return nil, fmt.Errorf("invalid -workdays %q", s)
+		}
+		days[d] = true
+	}
+	return days, nil
+}
+
+func dayIndex(name string) int {
+	for i, n := range weekdayOrder {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// freeSlots inverts busy (as returned by collapse) into the free gaps within
+// hours/days, clipped to [tmin, tmax).
+func freeSlots(busy []Event, tmin, tmax time.Time, hours workHours, days map[time.Weekday]bool, loc *time.Location) []Event {
+	var free []Event
+	day := truncDay(tmin.In(loc))
+	for !day.After(tmax) {
+		if days[day.Weekday()] {
+			winStart := day.Add(time.Duration(hours.startMin) * time.Minute)
+			winEnd := day.Add(time.Duration(hours.endMin) * time.Minute)
+			if winStart.Before(tmin) {
+				winStart = tmin
+			}
+			if winEnd.After(tmax) {
+				winEnd = tmax
+			}
+			if winStart.Before(winEnd) {
+				free = append(free, freeWithinWindow(winStart, winEnd, busy)...)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return free
+}
+
+// freeWithinWindow subtracts every busy interval that overlaps [start, end)
+// from that window.
+func freeWithinWindow(start, end time.Time, busy []Event) []Event {
+	var out []Event
+	cur := start
+	for _, b := range busy {
+		if !b.End.After(cur) || !b.Start.Before(end) {
+			continue
+		}
+		bs, be := b.Start, b.End
+		if bs.Before(cur) {
+			bs = cur
+		}
+		if be.After(end) {
+			be = end
+		}
+		if bs.After(cur) {
+			out = append(out, Event{Start: cur, End: bs})
+		}
+		if be.After(cur) {
+			cur = be
+		}
+	}
+	if cur.Before(end) {
+		out = append(out, Event{Start: cur, End: end})
+	}
+	return out
+}
+
+// findGap returns the earliest slot of at least min within free.
+func findGap(free []Event, min time.Duration) (Event, bool) {
+	for _, f := range free {
+		if f.End.Sub(f.Start) >= min {
+			return Event{Start: f.Start, End: f.Start.Add(min)}, true
+		}
+	}
+	return Event{}, false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreeSlots(t *testing.T) {
+	loc := time.UTC
+	hours := workHours{startMin: 9 * 60, endMin: 17 * 60}
+	days := map[time.Weekday]bool{time.Monday: true}
+
+	// Monday 2026-07-27, one meeting 10:00-11:00.
+	tmin := time.Date(2026, 7, 27, 0, 0, 0, 0, loc)
+	tmax := time.Date(2026, 7, 27, 23, 59, 0, 0, loc)
+	busy := []Event{
+		{Start: time.Date(2026, 7, 27, 10, 0, 0, 0, loc), End: time.Date(2026, 7, 27, 11, 0, 0, 0, loc)},
+	}
+
+	got := freeSlots(busy, tmin, tmax, hours, days, loc)
+	want := []Event{
+		{Start: time.Date(2026, 7, 27, 9, 0, 0, 0, loc), End: time.Date(2026, 7, 27, 10, 0, 0, 0, loc)},
+		{Start: time.Date(2026, 7, 27, 11, 0, 0, 0, loc), End: time.Date(2026, 7, 27, 17, 0, 0, 0, loc)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("freeSlots() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("freeSlots()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFreeSlotsSkipsNonWorkdays(t *testing.T) {
+	loc := time.UTC
+	hours := workHours{startMin: 9 * 60, endMin: 17 * 60}
+	days := map[time.Weekday]bool{time.Monday: true}
+
+	// Sunday 2026-07-26 isn't a workday, so no free slots should appear.
+	tmin := time.Date(2026, 7, 26, 0, 0, 0, 0, loc)
+	tmax := time.Date(2026, 7, 26, 23, 59, 0, 0, loc)
+
+	got := freeSlots(nil, tmin, tmax, hours, days, loc)
+	if len(got) != 0 {
+		t.Errorf("freeSlots() on a non-workday = %v, want none", got)
+	}
+}
+
+func TestFindGap(t *testing.T) {
+	loc := time.UTC
+	free := []Event{
+		{Start: time.Date(2026, 7, 27, 9, 0, 0, 0, loc), End: time.Date(2026, 7, 27, 9, 20, 0, 0, loc)},
+		{Start: time.Date(2026, 7, 27, 11, 0, 0, 0, loc), End: time.Date(2026, 7, 27, 17, 0, 0, 0, loc)},
+	}
+
+	slot, ok := findGap(free, 30*time.Minute)
+	if !ok {
+		t.Fatal("findGap() found no slot, want one at 11:00")
+	}
+	wantStart := time.Date(2026, 7, 27, 11, 0, 0, 0, loc)
+	wantEnd := wantStart.Add(30 * time.Minute)
+	if !slot.Start.Equal(wantStart) || !slot.End.Equal(wantEnd) {
+		t.Errorf("findGap() = %+v, want {%v %v}", slot, wantStart, wantEnd)
+	}
+
+	if _, ok := findGap(free, time.Hour*10); ok {
+		t.Error("findGap() found a slot longer than any available, want none")
+	}
+}
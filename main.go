@@ -3,15 +3,22 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -21,21 +28,123 @@ import (
 	"google.golang.org/api/option"
 )
 
+// calendarListTTL controls how long a cached CalendarList is trusted before
+// being refetched from the API.
+const calendarListTTL = 24 * time.Hour
+
 // Retrieve a token, saves the token, then returns the generated client.
-func getClient(dir string, config *oauth2.Config) *http.Client {
+func getClient(dir string, config *oauth2.Config, noBrowser bool) *http.Client {
 	// The file token.json stores the user's access and refresh tokens, and is
 	// created automatically when the authorization flow completes for the first
 	// time.
 	tokFile := filepath.Join(dir, "token.json")
 	tok, err := tokenFromFile(tokFile)
 	if err != nil {
-		tok = getTokenFromWeb(config)
+		tok = getToken(config, noBrowser)
 		saveToken(tokFile, tok)
 	}
 	return config.Client(context.Background(), tok)
 }
 
-// Request a token from the web, then returns the retrieved token.
+// getToken runs the loopback OAuth flow, falling back to the legacy
+// paste-the-code flow when noBrowser is set or the loopback server can't be
+// started (e.g. no display, sandboxed environment).
+func getToken(config *oauth2.Config, noBrowser bool) *oauth2.Token {
+	if noBrowser {
+		return getTokenFromWeb(config)
+	}
+	tok, err := getTokenFromLoopback(config)
+	if err != nil {
+		log.Printf("Unable to complete browser authorization (%v); falling back to manual code entry", err)
+		return getTokenFromWeb(config)
+	}
+	return tok
+}
+
+// getTokenFromLoopback starts a short-lived HTTP server on 127.0.0.1,
+// registers it as the OAuth redirect URI, opens the consent screen in the
+// user's browser, and waits for Google to redirect back with the code.
+// Google has deprecated the out-of-band (copy-paste code) flow this
+// replaces, so this is now the primary path.
+func getTokenFromLoopback(config *oauth2.Config) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting loopback listener: %w", err)
+	}
+	defer listener.Close()
+
+	state, err := randomState()
+	if err != nil {
+		return nil, fmt.Errorf("generating state token: %w", err)
+	}
+
+	origRedirect := config.RedirectURL
+	config.RedirectURL = fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+	defer func() { config.RedirectURL = origRedirect }()
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline)
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	resultCh := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			resultCh <- callbackResult{err: fmt.Errorf("state mismatch in callback")}
+			return
+		}
+		if msg := q.Get("error"); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			resultCh <- callbackResult{err: fmt.Errorf("authorization denied: %s", msg)}
+			return
+		}
+		fmt.Fprintln(w, "Authorization complete, you can close this window.")
+		resultCh <- callbackResult{code: q.Get("code")}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	fmt.Printf("Opening browser for authorization; if it doesn't open, visit:\n%v\n", authURL)
+	if err := openBrowser(authURL); err != nil {
+		log.Printf("Unable to open browser automatically: %v", err)
+	}
+
+	res := <-resultCh
+	if res.err != nil {
+		return nil, res.err
+	}
+	return config.Exchange(context.TODO(), res.code)
+}
+
+// randomState generates a CSRF token to bind the authorization request to
+// its callback.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// openBrowser launches the platform's default browser on url.
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("cmd", "/c", "start", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+// Request a token from the web, then returns the retrieved token. This is
+// the legacy out-of-band flow Google is deprecating; kept as a fallback for
+// -no-browser or headless use.
 func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 	fmt.Printf("Go to the following link in your browser then type the "+
@@ -76,6 +185,101 @@ func saveToken(path string, token *oauth2.Token) {
 	json.NewEncoder(f).Encode(token)
 }
 
+// calFlags collects repeated -cal flags. A leading "-" marks the pattern as
+// an exclusion; everything else is a glob matched against a calendar's
+// summary or id.
+type calFlags []string
+
+func (c *calFlags) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *calFlags) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
+// selected reports whether a calendar with the given summary/id should be
+// included, applying every -cal pattern in order. With no patterns, or with
+// only exclusion ("-pattern") patterns, every calendar is included by
+// default and exclusions remove from that; as soon as an inclusion pattern
+// is present, only calendars matching one are included. A later match, of
+// either kind, always wins over an earlier one.
+func (c calFlags) selected(summary, id string) bool {
+	hasInclude := false
+	for _, pattern := range c {
+		if !strings.HasPrefix(pattern, "-") {
+			hasInclude = true
+			break
+		}
+	}
+	include := !hasInclude
+	for _, pattern := range c {
+		exclude := strings.HasPrefix(pattern, "-")
+		p := strings.TrimPrefix(pattern, "-")
+		if matched, _ := filepath.Match(p, summary); matched {
+			include = !exclude
+			continue
+		}
+		if matched, _ := filepath.Match(p, id); matched {
+			include = !exclude
+		}
+	}
+	return include
+}
+
+// calendarListCache is the on-disk cache written to calendars.json,
+// mirroring the token.json pattern used for OAuth tokens above.
+type calendarListCache struct {
+	Fetched   time.Time                     `json:"fetched"`
+	Calendars []*calendar.CalendarListEntry `json:"calendars"`
+}
+
+// listCalendars returns the user's CalendarList, serving from a cache file
+// in dir when it is fresher than calendarListTTL and hitting the API
+// otherwise.
+func listCalendars(srv *calendar.Service, dir string) ([]*calendar.CalendarListEntry, error) {
+	cacheFile := filepath.Join(dir, "calendars.json")
+	if cached, err := calendarListFromFile(cacheFile); err == nil {
+		if time.Since(cached.Fetched) < calendarListTTL {
+			return cached.Calendars, nil
+		}
+	}
+
+	var entries []*calendar.CalendarListEntry
+	err := srv.CalendarList.List().Pages(context.Background(), func(l *calendar.CalendarList) error {
+		entries = append(entries, l.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	saveCalendarList(cacheFile, calendarListCache{Fetched: time.Now(), Calendars: entries})
+	return entries, nil
+}
+
+func calendarListFromFile(file string) (calendarListCache, error) {
+	var cache calendarListCache
+	f, err := os.Open(file)
+	if err != nil {
+		return cache, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&cache)
+	return cache, err
+}
+
+func saveCalendarList(path string, cache calendarListCache) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Printf("Unable to cache calendar list: %v", err)
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(cache)
+}
+
 func parseDay(d string) *time.Time {
 	for _, format := range []string{"2006-01-02", time.RFC3339} {
 		t, e := time.Parse(format, d)
@@ -117,35 +321,30 @@ func rspStatusFrom(event calendar.Event) string {
 
 var findUrl = regexp.MustCompile(`https?://(\S)+`)
 
-func zoomFrom(conf *calendar.ConferenceData) string {
-	if conf == nil {
-		return ""
-	}
-	for _, ep := range conf.EntryPoints {
-		if ep.MeetingCode != "" {
-			return fmt.Sprintf("%s (meeting:%s pass:%s)", ep.Uri, ep.MeetingCode, ep.Password)
-		}
-	}
-	return ""
-}
-
+// urlFrom returns the best link for joining or locating event: a recognized
+// conference provider's join URL first, then any bare URL in the location,
+// then the raw location text.
 func urlFrom(event calendar.Event) string {
-	var loc string
-
-	loc = findUrl.FindString(event.Location)
-	if loc == "" {
-		loc = zoomFrom(event.ConferenceData)
+	if m, ok := matchConference(event); ok && m.JoinURL != "" {
+		return m.JoinURL
 	}
-	if loc == "" {
-		loc = event.Location
+	if loc := findUrl.FindString(event.Location); loc != "" {
+		return loc
 	}
-	return loc
+	return event.Location
 }
 
 type Event struct {
 	Start, End time.Time
 }
 
+// taggedItem pairs a calendar API event with the CalendarListEntry it came
+// from, so printed lines can be attributed to their source calendar.
+type taggedItem struct {
+	item *calendar.Event
+	cal  *calendar.CalendarListEntry
+}
+
 // collapse ordered events
 func collapse(in []Event) []Event {
 	out := make([]Event, 0)
@@ -172,10 +371,48 @@ func collapse(in []Event) []Event {
 	return out
 }
 
+// eventsFor fetches the upcoming events for a single calendar in [tmin, tmax).
+func eventsFor(srv *calendar.Service, calID string, tmin, tmax time.Time) ([]*calendar.Event, error) {
+	var items []*calendar.Event
+	err := srv.Events.List(calID).ShowDeleted(false).SingleEvents(true).
+		TimeMin(tmin.Format(time.RFC3339)).TimeMax(tmax.Format(time.RFC3339)).
+		MaxResults(100).OrderBy("startTime").Pages(context.Background(), func(l *calendar.Events) error {
+		items = append(items, l.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func main() {
 	summarize := flag.Bool("s", false, "summarize")
 	dur := flag.Duration("t", time.Hour*24*7, "duration")
+	var cals calFlags
+	flag.Var(&cals, "cal", "calendar summary/id glob to include, or -pattern to exclude; repeatable")
+	noBrowser := flag.Bool("no-browser", false, "use the manual paste-the-code auth flow instead of the browser loopback")
+	free := flag.Bool("free", false, "show free slots within work hours instead of busy events")
+	find := flag.Duration("find", 0, "print the earliest free slot of at least this duration, e.g. 30m")
+	hoursFlag := flag.String("hours", "09:00-17:00", "work hours for -free/-find, HH:MM-HH:MM")
+	tzFlag := flag.String("tz", "Local", "time zone for -free/-find")
+	workdaysFlag := flag.String("workdays", "mon-fri", "work days for -free/-find, e.g. mon-fri or mon,wed,fri")
+	outFormat := flag.String("o", "text", "output format: text, json, ics, or tsv")
+	track := flag.Bool("track", false, "emit a time-tracking timesheet for a past date range instead of upcoming events")
+	trackFrom := flag.String("from", "", "start date for -track, e.g. 2026-07-01")
+	trackTo := flag.String("to", "", "end date for -track, e.g. 2026-07-08")
+	trackCSV := flag.Bool("track-csv", false, "emit -track output as CSV instead of a table")
+	projectRegex := flag.String("project-regex", `^\[(?P<project>[^\]]+)\]`, "regex with a 'project' named group applied to summaries for -track")
+	join := flag.Bool("join", false, "open the next meeting's conference link in the browser")
+	watch := flag.Bool("watch", false, "keep running, notifying before each upcoming meeting")
+	watchInterval := flag.Duration("watch-interval", 5*time.Minute, "how often -watch polls for events")
+	watchBefore := flag.Duration("watch-before", 10*time.Minute, "how far ahead of a meeting -watch notifies")
 	flag.Parse()
+
+	formatter, err := formatterFor(*outFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
 	ex, err := os.Executable()
 	if err != nil {
 		log.Fatal(err)
@@ -195,77 +432,168 @@ func main() {
 	if err != nil {
 		log.Fatalf("Unable to parse client secret file to config: %v", err)
 	}
-	client := getClient(dir, config)
+	client := getClient(dir, config, *noBrowser)
 	srv, err := calendar.NewService(context.Background(), option.WithHTTPClient(client))
 	if err != nil {
 		log.Fatalf("Unable to retrieve Calendar client: %v", err)
 	}
 
+	calendars, err := listCalendars(srv, dir)
+	if err != nil {
+		log.Fatalf("Unable to retrieve calendar list: %v", err)
+	}
+	var selected []*calendar.CalendarListEntry
+	for _, c := range calendars {
+		if cals.selected(c.Summary, c.Id) {
+			selected = append(selected, c)
+		}
+	}
+	if len(calendars) == 0 {
+		// CalendarList.List doesn't always include "primary" for every
+		// account setup; fall back to it so the tool still works with no
+		// cache/API access to the list. If the list was non-empty but -cal
+		// filtered everything out, respect that and show nothing.
+		selected = []*calendar.CalendarListEntry{{Id: "primary", Summary: "primary"}}
+	}
+
+	if *track {
+		runTrack(srv, selected, *trackFrom, *trackTo, *projectRegex, *trackCSV)
+		return
+	}
+
+	if *watch {
+		runWatch(srv, selected, dir, *watchInterval, *watchBefore)
+		return
+	}
+
 	tmin := time.Now()
 	tmax := tmin.Add(*dur)
-	events, err := srv.Events.List("primary").ShowDeleted(false).SingleEvents(true).
-		TimeMin(tmin.Format(time.RFC3339)).TimeMax(tmax.Format(time.RFC3339)).
-		MaxResults(100).OrderBy("startTime").Do()
-	if err != nil {
-		log.Fatalf("Unable to retrieve events: %v", err)
+	var items []taggedItem
+	for _, c := range selected {
+		its, err := eventsFor(srv, c.Id, tmin, tmax)
+		if err != nil {
+			log.Fatalf("Unable to retrieve events for %s: %v", c.Id, err)
+		}
+		for _, item := range its {
+			items = append(items, taggedItem{item: item, cal: c})
+		}
 	}
-	if len(events.Items) == 0 {
-		fmt.Println("No upcoming events found.")
-	} else {
-		prevDay := truncDay(time.Now())
+	sort.Slice(items, func(i, j int) bool {
+		ti, tj := parseDay(startDateFrom(*items[i].item)), parseDay(startDateFrom(*items[j].item))
+		if ti == nil || tj == nil {
+			return startDateFrom(*items[i].item) < startDateFrom(*items[j].item)
+		}
+		return ti.Before(*tj)
+	})
 
-		evs := make([]Event, 0)
-		for _, item := range events.Items {
-			// skip specially colored items
-			// I use this for AFK time
-			if item.ColorId != "" {
+	if *join {
+		for _, ti := range items {
+			item := ti.item
+			if item.ColorId != "" || rspStatusFrom(*item) == "declined" {
 				continue
 			}
-			rspStatus := rspStatusFrom(*item)
-			if rspStatus == "declined" {
-				continue
-			}
-			ev := Event{}
-			startDate := startDateFrom(*item)
-			var day time.Time
-			if d := parseDay(startDate); d != nil {
-				day = truncDay(*d)
-				startDate = d.Format("2006-01-02 15:04")
-				ev.Start = *d
+			if m, ok := matchConference(*item); ok && m.JoinURL != "" {
+				fmt.Printf("Opening %s for %q: %s\n", m.Provider, item.Summary, m.JoinURL)
+				if err := openBrowser(m.JoinURL); err != nil {
+					log.Fatalf("Unable to open browser: %v", err)
+				}
+				return
 			}
-			endDate := endDateFrom(*item)
-			if d := parseDay(endDate); d != nil {
-				endDate = d.Format("15:04")
-				ev.End = *d
+		}
+		fmt.Println("No upcoming meeting with a conference link found.")
+		return
+	}
+
+	quiet := *free || *find > 0
+	evs := make([]Event, 0)
+	formatted := make([]FormattedEvent, 0)
+	for _, ti := range items {
+		item := ti.item
+		// skip specially colored items
+		// I use this for AFK time
+		if item.ColorId != "" {
+			continue
+		}
+		rspStatus := rspStatusFrom(*item)
+		if rspStatus == "declined" {
+			continue
+		}
+		ev := Event{}
+		if d := parseDay(startDateFrom(*item)); d != nil {
+			ev.Start = *d
+		}
+		if d := parseDay(endDateFrom(*item)); d != nil {
+			ev.End = *d
+		}
+		evs = append(evs, ev)
+		if !*summarize && !quiet {
+			formatted = append(formatted, FormattedEvent{
+				Start:          ev.Start,
+				End:            ev.End,
+				Summary:        item.Summary,
+				Location:       item.Location,
+				URL:            urlFrom(*item),
+				ResponseStatus: rspStatus,
+				HtmlLink:       item.HtmlLink,
+				Conference:     conferenceEntryPoints(item.ConferenceData),
+				CalendarID:     ti.cal.Id,
+				CalendarName:   ti.cal.Summary,
+			})
+		}
+	}
+	if !*summarize && !quiet {
+		if err := formatter.Format(os.Stdout, formatted, len(selected) > 1); err != nil {
+			log.Fatalf("Unable to format events: %v", err)
+		}
+	}
+	if *summarize && !quiet {
+		c := collapse(evs)
+		prevDay := truncDay(time.Now())
+		for _, i := range c {
+			day := truncDay(i.Start)
+			if day != prevDay {
+				fmt.Println("----------------------")
+				prevDay = day
 			}
-			if *summarize {
-				// probably not going to be big
-				evs = append(evs, ev)
+			startDate := i.Start.Format("2006-01-02 15:04")
+			endDate := i.End.Format("15:04")
+			fmt.Printf("%s-%s\n", startDate, endDate)
+		}
+	}
+
+	if quiet {
+		loc, err := time.LoadLocation(*tzFlag)
+		if err != nil {
+			log.Fatalf("Invalid -tz %q: %v", *tzFlag, err)
+		}
+		hours, err := parseWorkHours(*hoursFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		days, err := parseWorkdays(*workdaysFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		freeList := freeSlots(collapse(evs), tmin, tmax, hours, days, loc)
+
+		if *find > 0 {
+			slot, ok := findGap(freeList, *find)
+			if !ok {
+				fmt.Println("No free slot found in the query window.")
 			} else {
-				if day != prevDay {
-					fmt.Println("----------------------")
-					prevDay = day
-				}
-				fmt.Printf("%s-%s %-40s %s", startDate, endDate, item.Summary, urlFrom(*item))
-				if rspStatus != "" && rspStatus != "accepted" {
-					fmt.Printf(" [%s: %s]", rspStatus, item.HtmlLink)
-				}
-				fmt.Println()
+				fmt.Printf("%s-%s\n", slot.Start.In(loc).Format("2006-01-02 15:04"), slot.End.In(loc).Format("15:04"))
 			}
+			return
 		}
-		if *summarize {
-			c := collapse(evs)
-			prevDay := truncDay(time.Now())
-			for _, i := range c {
-				day := truncDay(i.Start)
-				if day != prevDay {
-					fmt.Println("----------------------")
-					prevDay = day
-				}
-				startDate := i.Start.Format("2006-01-02 15:04")
-				endDate := i.End.Format("15:04")
-				fmt.Printf("%s-%s\n", startDate, endDate)
+
+		prevDay := truncDay(tmin.In(loc))
+		for _, f := range freeList {
+			day := truncDay(f.Start.In(loc))
+			if day != prevDay {
+				fmt.Println("----------------------")
+				prevDay = day
 			}
+			fmt.Printf("%s-%s\n", f.Start.In(loc).Format("2006-01-02 15:04"), f.End.In(loc).Format("15:04"))
 		}
 	}
 }
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCalFlagsSelected(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       calFlags
+		summary string
+		id      string
+		want    bool
+	}{
+		{"no patterns includes everything", nil, "Work", "work@group.calendar.google.com", true},
+		{"pure exclude list is a blocklist", calFlags{"-Holidays"}, "Work", "work@group.calendar.google.com", true},
+		{"pure exclude list still excludes its match", calFlags{"-Holidays"}, "Holidays", "en.usa#holiday@group.v.calendar.google.com", false},
+		{"any include pattern makes it an allowlist", calFlags{"Work"}, "Personal", "personal@group.calendar.google.com", false},
+		{"include pattern matches", calFlags{"Work"}, "Work", "work@group.calendar.google.com", true},
+		{"later pattern wins over earlier", calFlags{"Work*", "-Work Secrets"}, "Work Secrets", "secrets@group.calendar.google.com", false},
+		{"id match works same as summary", calFlags{"-vacation@group.calendar.google.com"}, "Vacation", "vacation@group.calendar.google.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.selected(tt.summary, tt.id); got != tt.want {
+				t.Errorf("calFlags(%v).selected(%q, %q) = %v, want %v", tt.c, tt.summary, tt.id, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TrackEntry is a single 15-minute-rounded, coalesced calendar event within a
+// -track timesheet.
+type TrackEntry struct {
+	Start, End time.Time
+	Summary    string
+	Project    string
+}
+
+// Day is the calendar day the entry falls on.
+func (t TrackEntry) Day() time.Time { return truncDay(t.Start) }
+
+// Duration is the rounded length of the entry.
+func (t TrackEntry) Duration() time.Duration { return t.End.Sub(t.Start) }
+
+// trackEntries rounds each event's start/end to the nearest 15 minutes,
+// drops anything that rounds to zero length, tags each with its project via
+// projectRe, and coalesces adjacent events that share a summary.
+func trackEntries(events []FormattedEvent, projectRe *regexp.Regexp) []TrackEntry {
+	var rounded []TrackEntry
+	for _, e := range events {
+		start := e.Start.Round(15 * time.Minute)
+		end := e.End.Round(15 * time.Minute)
+		if !end.After(start) {
+			continue
+		}
+		rounded = append(rounded, TrackEntry{
+			Start:   start,
+			End:     end,
+			Summary: e.Summary,
+			Project: projectFor(e.Summary, projectRe),
+		})
+	}
+	sort.Slice(rounded, func(i, j int) bool { return rounded[i].Start.Before(rounded[j].Start) })
+
+	var coalesced []TrackEntry
+	for _, r := range rounded {
+		if n := len(coalesced); n > 0 && coalesced[n-1].Summary == r.Summary && !coalesced[n-1].End.Before(r.Start) {
+			if r.End.After(coalesced[n-1].End) {
+				coalesced[n-1].End = r.End
+			}
+			continue
+		}
+		coalesced = append(coalesced, r)
+	}
+	return coalesced
+}
+
+// projectFor applies re's "project" named group to summary, returning "" if
+// re is nil or doesn't match.
+func projectFor(summary string, re *regexp.Regexp) string {
+	if re == nil {
+		return ""
+	}
+	m := re.FindStringSubmatch(summary)
+	if m == nil {
+		return ""
+	}
+	for i, name := range re.SubexpNames() {
+		if name == "project" {
+			return m[i]
+		}
+	}
+	return ""
+}
+
+// writeTrackTable renders entries as a per-day timesheet with a project
+// breakdown, e.g. for terminal viewing.
+func writeTrackTable(w io.Writer, entries []TrackEntry) {
+	projectTotals := make(map[string]time.Duration)
+	var prevDay time.Time
+	var dayTotal time.Duration
+	for i, e := range entries {
+		if e.Day() != prevDay {
+			if i > 0 {
+				fmt.Fprintf(w, "  Total: %.2fh\n\n", dayTotal.Hours())
+			}
+			fmt.Fprintln(w, e.Day().Format("2006-01-02"))
+			prevDay = e.Day()
+			dayTotal = 0
+		}
+		fmt.Fprintf(w, "  %s-%s %-40s %.2fh\n", e.Start.Format("15:04"), e.End.Format("15:04"), e.Summary, e.Duration().Hours())
+		dayTotal += e.Duration()
+		projectTotals[e.Project] += e.Duration()
+	}
+	if len(entries) > 0 {
+		fmt.Fprintf(w, "  Total: %.2fh\n\n", dayTotal.Hours())
+	}
+
+	fmt.Fprintln(w, "Project breakdown:")
+	projects := make([]string, 0, len(projectTotals))
+	for p := range projectTotals {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+	for _, p := range projects {
+		name := p
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "  %-20s %.2fh\n", name, projectTotals[p].Hours())
+	}
+}
+
+// writeTrackCSV renders entries as CSV suitable for import into billing
+// systems.
+func writeTrackCSV(w io.Writer, entries []TrackEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"day", "start", "end", "hours", "project", "summary"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Day().Format("2006-01-02"),
+			e.Start.Format("15:04"),
+			e.End.Format("15:04"),
+			fmt.Sprintf("%.2f", e.Duration().Hours()),
+			e.Project,
+			e.Summary,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runTrack fetches events for [from, to) across the selected calendars and
+// prints a time-tracking timesheet instead of the usual upcoming-events
+// listing.
+func runTrack(srv *calendar.Service, selected []*calendar.CalendarListEntry, from, to, projectRegex string, asCSV bool) {
+	tmin := parseDay(from)
+	if tmin == nil {
+		log.Fatalf("Invalid -from %q, want YYYY-MM-DD", from)
+	}
+	tmax := parseDay(to)
+	if tmax == nil {
+		log.Fatalf("Invalid -to %q, want YYYY-MM-DD", to)
+	}
+
+	projectRe, err := regexp.Compile(projectRegex)
+	if err != nil {
+		log.Fatalf("Invalid -project-regex %q: %v", projectRegex, err)
+	}
+
+	var formatted []FormattedEvent
+	for _, c := range selected {
+		items, err := eventsFor(srv, c.Id, *tmin, *tmax)
+		if err != nil {
+			log.Fatalf("Unable to retrieve events for %s: %v", c.Id, err)
+		}
+		for _, item := range items {
+			// skip specially colored items
+			// I use this for AFK time
+			if item.ColorId != "" {
+				continue
+			}
+			if rspStatusFrom(*item) == "declined" {
+				continue
+			}
+			start := parseDay(startDateFrom(*item))
+			end := parseDay(endDateFrom(*item))
+			if start == nil || end == nil {
+				continue
+			}
+			formatted = append(formatted, FormattedEvent{Start: *start, End: *end, Summary: item.Summary})
+		}
+	}
+
+	entries := trackEntries(formatted, projectRe)
+	if asCSV {
+		if err := writeTrackCSV(os.Stdout, entries); err != nil {
+			log.Fatalf("Unable to write CSV: %v", err)
+		}
+		return
+	}
+	writeTrackTable(os.Stdout, entries)
+}
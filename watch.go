@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// watchState is the on-disk record of event IDs already notified, so a
+// restarted -watch doesn't re-notify for meetings it already announced.
+type watchState struct {
+	Notified map[string]time.Time `json:"notified"`
+}
+
+func loadWatchState(path string) watchState {
+	state := watchState{Notified: make(map[string]time.Time)}
+	f, err := os.Open(path)
+	if err != nil {
+		return state
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&state); err != nil || state.Notified == nil {
+		state.Notified = make(map[string]time.Time)
+	}
+	return state
+}
+
+func saveWatchState(path string, state watchState) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Printf("Unable to save watch state: %v", err)
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(state)
+}
+
+// notify fires a desktop notification on the current platform.
+func notify(title, body string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		return exec.Command("osascript", "-e", script).Run()
+	case "windows":
+		return exec.Command("toast", "-t", title, "-m", body).Run()
+	default:
+		return exec.Command("notify-send", title, body).Run()
+	}
+}
+
+// runWatch polls the selected calendars every interval and fires a desktop
+// notification before minutes ahead of each upcoming meeting's start. It
+// never returns.
+func runWatch(srv *calendar.Service, selected []*calendar.CalendarListEntry, dir string, interval, before time.Duration) {
+	statePath := filepath.Join(dir, "watch-state.json")
+	state := loadWatchState(statePath)
+
+	for {
+		now := time.Now()
+		var items []taggedItem
+		for _, c := range selected {
+			its, err := eventsFor(srv, c.Id, now, now.Add(before+interval))
+			if err != nil {
+				log.Printf("Unable to retrieve events for %s: %v", c.Id, err)
+				continue
+			}
+			for _, item := range its {
+				items = append(items, taggedItem{item: item, cal: c})
+			}
+		}
+
+		for _, ti := range items {
+			item := ti.item
+			// skip specially colored items
+			// I use this for AFK time
+			if item.ColorId != "" || rspStatusFrom(*item) == "declined" {
+				continue
+			}
+			if _, done := state.Notified[item.Id]; done {
+				continue
+			}
+			start := parseDay(startDateFrom(*item))
+			if start == nil || start.Sub(now) > before {
+				continue
+			}
+
+			body := item.Summary
+			if joinURL := urlFrom(*item); joinURL != "" {
+				body = fmt.Sprintf("%s\n%s", item.Summary, joinURL)
+			}
+			if err := notify(fmt.Sprintf("Meeting at %s", start.Format("15:04")), body); err != nil {
+				log.Printf("Unable to send notification: %v", err)
+			}
+			state.Notified[item.Id] = now
+			saveWatchState(statePath, state)
+		}
+
+		for id, at := range state.Notified {
+			if now.Sub(at) > 24*time.Hour {
+				delete(state.Notified, id)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}